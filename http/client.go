@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+/**
+* A simple HTTP client used to call the mocked test server.
+* @author rnojiri
+**/
+
+// buildRequest - assembles the *http.Request for request, encoding its Parts
+// as multipart when present
+func buildRequest(method, url string, request *RequestData) *http.Request {
+
+	body := request.Body
+	var multipartContentType string
+
+	if len(request.Parts) > 0 {
+		var err error
+		body, multipartContentType, err = encodeMultipartBody(request.Parts)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		panic(err)
+	}
+
+	if request.Headers != nil {
+		req.Header = request.Headers
+	}
+
+	if multipartContentType != "" {
+		req.Header.Set("Content-Type", multipartContentType)
+	}
+
+	return req
+}
+
+// doWithClient - runs req through client and converts the result into a
+// ResponseData carrying host/port for the caller's convenience
+func doWithClient(client *http.Client, req *http.Request, host string, port int) *ResponseData {
+
+	resp, err := client.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	return &ResponseData{
+		RequestData: RequestData{
+			Method:  req.Method,
+			URI:     req.URL.Path,
+			Body:    string(respBody),
+			Headers: resp.Header,
+			Host:    host,
+			Port:    port,
+		},
+		Status: resp.StatusCode,
+	}
+}
+
+// DoRequest - performs a request against the mocked test server
+func DoRequest(host string, port int, request *RequestData) *ResponseData {
+
+	url := fmt.Sprintf("http://%s:%d%s", host, port, request.URI)
+	req := buildRequest(request.Method, url, request)
+
+	return doWithClient(&http.Client{}, req, host, port)
+}
+
+// DoTLSRequest - performs a request against a mocked test server configured
+// with Configuration.TLS. certPool, typically obtained from
+// Server.ClientCertPool, is used to verify the server's certificate; when
+// the server requires mTLS, clientCert must be the certificate it will
+// accept, typically Server.ClientCertificate
+func DoTLSRequest(host string, port int, request *RequestData, certPool *x509.CertPool, clientCert *tls.Certificate) *ResponseData {
+
+	url := fmt.Sprintf("https://%s:%d%s", host, port, request.URI)
+	req := buildRequest(request.Method, url, request)
+
+	tlsConfig := &tls.Config{RootCAs: certPool}
+	if clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+
+	return doWithClient(client, req, host, port)
+}
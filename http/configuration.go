@@ -0,0 +1,34 @@
+package http
+
+/**
+* The configuration types used by the test server.
+* @author rnojiri
+**/
+
+// Configuration - the test server configuration
+type Configuration struct {
+	Host        string
+	Port        int
+	ChannelSize int
+	Responses   map[string][]ResponseData
+
+	// Recorder, when set, proxies requests unmatched by Responses, Scripts,
+	// Handlers and Replay to an upstream server and records the round-trip
+	// to a transcript file
+	Recorder *RecorderMode
+
+	// Replay, when set, serves requests unmatched by Responses, Scripts and
+	// Handlers from a previously recorded transcript file
+	Replay *ReplayMode
+
+	// Scripts, keyed by mode, serve an ordered sequence of responses per
+	// (URI, Method) pair, one response per call
+	Scripts map[string][]ResponseScript
+
+	// Handlers, keyed by mode, compute a response dynamically per
+	// (URI, Method) pair, reading and mutating the server's shared state
+	Handlers map[string][]RouteHandler
+
+	// TLS, when set, makes the server listen over HTTPS instead of plaintext
+	TLS *TLSConfig
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+/**
+* Fault injection applied when writing a configured response, so tests can
+* simulate timeouts, truncated bodies and flaky upstreams.
+* @author rnojiri
+**/
+
+// applyResponse - writes response to w, honoring any fault-injection knobs
+// configured on response.Faults
+func (s *Server) applyResponse(w http.ResponseWriter, response *ResponseData) {
+
+	faults := response.Faults
+
+	wait := response.Wait
+	if faults != nil && faults.JitterWait > 0 {
+		wait += time.Duration(rand.Int63n(int64(faults.JitterWait)))
+	}
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+
+	if faults != nil && faults.DropConnection {
+		hijackAndClose(w)
+		return
+	}
+
+	for header, values := range response.Headers {
+		for _, value := range values {
+			w.Header().Add(header, value)
+		}
+	}
+
+	status := response.Status
+	if faults != nil && len(faults.RandomStatus) > 0 {
+		status = pickWeightedStatus(faults.RandomStatus)
+	}
+
+	switch {
+	case faults != nil && faults.SlowBody > 0:
+		writeSlowBody(w, status, response.Body, faults.SlowBody)
+	case faults != nil && faults.PartialBody > 0:
+		writePartialBody(w, status, response.Body, faults.PartialBody)
+	default:
+		w.WriteHeader(status)
+		w.Write([]byte(response.Body))
+	}
+}
+
+// pickWeightedStatus - picks one of candidates at random, weighted by
+// WeightedStatus.Weight. Entries with a non-positive weight are never
+// picked unless every candidate is non-positive, in which case the first
+// candidate is returned
+func pickWeightedStatus(candidates []WeightedStatus) int {
+
+	total := 0
+	for _, candidate := range candidates {
+		if candidate.Weight > 0 {
+			total += candidate.Weight
+		}
+	}
+
+	if total == 0 {
+		return candidates[0].Status
+	}
+
+	pick := rand.Intn(total)
+
+	for _, candidate := range candidates {
+		if candidate.Weight <= 0 {
+			continue
+		}
+		if pick < candidate.Weight {
+			return candidate.Status
+		}
+		pick -= candidate.Weight
+	}
+
+	return candidates[len(candidates)-1].Status
+}
+
+// writeSlowBody - writes body one byte at a time, flushing and then sleeping
+// delay after each byte (the last one included), to simulate a slow upstream
+func writeSlowBody(w http.ResponseWriter, status int, body string, delay time.Duration) {
+
+	w.WriteHeader(status)
+
+	flusher, _ := w.(http.Flusher)
+
+	for i := 0; i < len(body); i++ {
+		w.Write([]byte{body[i]})
+		if flusher != nil {
+			flusher.Flush()
+		}
+		time.Sleep(delay)
+	}
+}
+
+// writePartialBody - writes only the first n bytes of body and then closes
+// the connection, to simulate a truncated response
+func writePartialBody(w http.ResponseWriter, status int, body string, n int) {
+
+	if n > len(body) {
+		n = len(body)
+	}
+
+	w.WriteHeader(status)
+	w.Write([]byte(body[:n]))
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	hijackAndClose(w)
+}
+
+// hijackAndClose - takes over the connection and closes it without writing
+// anything further, simulating a server that drops the connection
+func hijackAndClose(w http.ResponseWriter) {
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	conn.Close()
+}
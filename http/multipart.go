@@ -0,0 +1,167 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+/**
+* Multipart request parsing and encoding, so file-upload endpoints can be
+* mocked and asserted against without hand-building multipart bodies.
+* @author rnojiri
+**/
+
+// multipartBoundary - the boundary gotesthttp always encodes with, so tests
+// can assert on request bodies without worrying about a random boundary
+const multipartBoundary = "gotesthttpboundary"
+
+// MultipartPart - a single part of a multipart request
+type MultipartPart struct {
+	MediaType string
+	Headers   textproto.MIMEHeader
+	Body      string
+	FileName  string
+
+	// Name sets the Content-Disposition form field name. If empty, a
+	// unique default ("field0", "field1", ... for plain parts, "file0",
+	// "file1", ... for parts with a FileName) is generated, so multiple
+	// parts of the same kind never collide on the same field name
+	Name string
+}
+
+// isMultipart - tells whether a Content-Type header denotes a multipart body
+func isMultipart(contentType string) bool {
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && strings.HasPrefix(mediaType, "multipart/")
+}
+
+// parseMultipartBody - parses a raw multipart body into its parts
+func parseMultipartBody(contentType string, body []byte) ([]MultipartPart, error) {
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	boundary, ok := params["boundary"]
+	if !ok {
+		return nil, fmt.Errorf("gotesthttp: multipart content-type missing boundary")
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+
+	var parts []MultipartPart
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, MultipartPart{
+			MediaType: part.Header.Get("Content-Type"),
+			Headers:   part.Header,
+			Body:      string(data),
+			FileName:  part.FileName(),
+			Name:      part.FormName(),
+		})
+	}
+
+	return parts, nil
+}
+
+// encodeMultipartBody - encodes parts into a multipart body using a stable
+// boundary, returning the body and its matching Content-Type header value
+func encodeMultipartBody(parts []MultipartPart) (string, string, error) {
+
+	var buf bytes.Buffer
+
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(multipartBoundary); err != nil {
+		return "", "", err
+	}
+
+	fieldIndex, fileIndex := 0, 0
+
+	for _, part := range parts {
+
+		header := textproto.MIMEHeader{}
+		for key, values := range part.Headers {
+			header[key] = values
+		}
+
+		if header.Get("Content-Disposition") == "" {
+
+			name := part.Name
+
+			if part.FileName != "" {
+				if name == "" {
+					name = fmt.Sprintf("file%d", fileIndex)
+				}
+				fileIndex++
+				header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, name, part.FileName))
+			} else {
+				if name == "" {
+					name = fmt.Sprintf("field%d", fieldIndex)
+				}
+				fieldIndex++
+				header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, name))
+			}
+		}
+
+		if header.Get("Content-Type") == "" && part.MediaType != "" {
+			header.Set("Content-Type", part.MediaType)
+		}
+
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return "", "", err
+		}
+
+		if _, err := partWriter.Write([]byte(part.Body)); err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", "", err
+	}
+
+	return buf.String(), writer.FormDataContentType(), nil
+}
+
+// EqualMultipartParts - compares two sets of multipart parts, in order, by
+// MediaType, FileName and Body, irrespective of the boundary string used to
+// encode them. Name and Headers are not compared
+func EqualMultipartParts(expected, actual []MultipartPart) bool {
+
+	if len(expected) != len(actual) {
+		return false
+	}
+
+	for i := range expected {
+		if expected[i].MediaType != actual[i].MediaType ||
+			expected[i].FileName != actual[i].FileName ||
+			expected[i].Body != actual[i].Body {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+/**
+* Record/replay support: proxies unmatched requests to an upstream server,
+* appending the full round-trip to a newline-delimited JSON transcript file,
+* and replays a previously recorded transcript deterministically.
+* @author rnojiri
+**/
+
+// BodyScrubber - redacts a request before it is persisted to a transcript.
+// It is handed the request by pointer and may mutate Headers in place (for
+// example to strip an Authorization header) in addition to returning the
+// bytes that replace Request.Body; both apply only to the persisted copy
+type BodyScrubber func(*RequestData) []byte
+
+// RecorderMode - proxies requests unmatched by the configured responses to an
+// upstream server, appending each round-trip to a transcript file
+type RecorderMode struct {
+	UpstreamBaseURL string
+	TranscriptPath  string
+	Scrub           BodyScrubber
+}
+
+// ReplayMode - serves requests from a previously recorded transcript file
+type ReplayMode struct {
+	TranscriptPath string
+
+	// Scrub, when set, must match the BodyScrubber used to record the
+	// transcript, so a live request's signature is normalized the same way
+	// a persisted entry's signature was before being looked up
+	Scrub BodyScrubber
+}
+
+// TranscriptEntry - a single recorded request/response exchange
+type TranscriptEntry struct {
+	Request   RequestData
+	Response  ResponseData
+	Timestamp time.Time
+	Duration  time.Duration
+}
+
+// requestSignature - builds the key used to correlate a request with a
+// recorded transcript entry
+func requestSignature(method, uri, body string) string {
+
+	return method + " " + uri + "\n" + body
+}
+
+// replaySignature - builds the signature for a live incoming request,
+// applying scrub to its body first (if configured) so it matches the
+// signature computed from the already-scrubbed body of a recorded entry
+func replaySignature(scrub BodyScrubber, request *RequestData) string {
+
+	body := request.Body
+
+	if scrub != nil {
+		scrubbed := *request
+		scrubbed.Headers = cloneHeader(request.Headers)
+		body = string(scrub(&scrubbed))
+	}
+
+	return requestSignature(request.Method, request.URI, body)
+}
+
+// loadTranscript - reads a transcript file into a signature-indexed map of responses
+func loadTranscript(path string) (map[string]*ResponseData, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index := map[string]*ResponseData{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry TranscriptEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, err
+		}
+
+		response := entry.Response
+		signature := requestSignature(entry.Request.Method, entry.Request.URI, entry.Request.Body)
+		index[signature] = &response
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// appendTranscript - appends a recorded exchange to the transcript file,
+// scrubbing the request body first when a scrubber is configured
+func appendTranscript(path string, entry *TranscriptEntry, scrub BodyScrubber) error {
+
+	if scrub != nil {
+		entry.Request.Headers = cloneHeader(entry.Request.Headers)
+		entry.Request.Body = string(scrub(&entry.Request))
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+
+	return err
+}
+
+// cloneHeader - returns a deep copy of header, so a BodyScrubber can redact
+// it without mutating the live request it was copied from
+func cloneHeader(header http.Header) http.Header {
+
+	clone := make(http.Header, len(header))
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+
+	return clone
+}
+
+// proxyToUpstream - forwards a request to the recorder's upstream base URL and
+// returns the response produced, alongside how long the round-trip took
+func proxyToUpstream(baseURL string, request *RequestData) (*ResponseData, time.Duration, error) {
+
+	start := time.Now()
+
+	req, err := http.NewRequest(request.Method, baseURL+request.URI, bytes.NewBufferString(request.Body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header = request.Headers
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	response := &ResponseData{
+		RequestData: RequestData{
+			Body:    string(body),
+			Headers: resp.Header,
+		},
+		Status: resp.StatusCode,
+	}
+
+	return response, time.Since(start), nil
+}
@@ -0,0 +1,37 @@
+package http
+
+import "net/http"
+
+/**
+* The request types used by the test server and client.
+* @author rnojiri
+**/
+
+// RequestData - stores a request send or received by the test server
+type RequestData struct {
+	URI     string
+	Body    string
+	Method  string
+	Headers http.Header
+	Host    string
+	Port    int
+
+	// QueryMatch, when configured on a response, requires the incoming
+	// request to carry each of these query parameters with matching values
+	QueryMatch map[string]string
+
+	// BodyMatch, when configured on a response, requires the incoming
+	// request body to satisfy this pattern, either a regular expression or,
+	// when it looks like a JSON object, a JSON subset
+	BodyMatch string
+
+	// PathParams holds the values captured from the URI pattern of the
+	// matched response, keyed by wildcard name. It is only populated on
+	// requests received by the server.
+	PathParams map[string]string
+
+	// Parts, when set on a client request, is encoded into a multipart body
+	// instead of Body. On a request received by the server, it is populated
+	// by parsing the incoming body whenever Content-Type is multipart/*.
+	Parts []MultipartPart
+}
@@ -0,0 +1,51 @@
+package http
+
+import "time"
+
+/**
+* The response types used by the test server and client.
+* @author rnojiri
+**/
+
+// ResponseData - stores a response configured on the test server or returned by the client
+type ResponseData struct {
+	RequestData
+	Status int
+	Wait   time.Duration
+
+	// Faults, when set, injects connection or body-level misbehavior into
+	// this response, for testing how clients handle a flaky upstream
+	Faults *Faults
+}
+
+// Faults - fault-injection knobs applied when the server writes a response,
+// for testing timeouts, truncated bodies and flaky upstreams
+type Faults struct {
+	// DropConnection, when true, hijacks the connection and closes it
+	// without writing a status line or body
+	DropConnection bool
+
+	// PartialBody, when > 0, writes only the first PartialBody bytes of the
+	// body and then closes the connection, instead of the full body.
+	// Ignored if SlowBody is also set.
+	PartialBody int
+
+	// SlowBody, when > 0, writes the body one byte at a time, flushing and
+	// then sleeping SlowBody after each one. Takes precedence
+	// over PartialBody when both are set.
+	SlowBody time.Duration
+
+	// RandomStatus, when non-empty, overrides Status by picking one of its
+	// entries at random, weighted by WeightedStatus.Weight
+	RandomStatus []WeightedStatus
+
+	// JitterWait, when > 0, adds a random duration in [0, JitterWait) to Wait
+	JitterWait time.Duration
+}
+
+// WeightedStatus - a candidate status code for Faults.RandomStatus and its
+// relative weight among the other candidates
+type WeightedStatus struct {
+	Status int
+	Weight int
+}
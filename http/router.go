@@ -0,0 +1,276 @@
+package http
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+/**
+* A routing trie that resolves a configured ResponseData for an incoming
+* request URI, supporting path parameters (e.g. "/users/{id}") in addition
+* to plain literal matching.
+* @author rnojiri
+**/
+
+var paramTypeValidators = map[string]*regexp.Regexp{
+	"int":  regexp.MustCompile(`^-?[0-9]+$`),
+	"uuid": regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`),
+}
+
+// routeNode - a single segment of the routing trie
+type routeNode struct {
+	literalChildren map[string]*routeNode
+	paramChild      *routeNode
+	paramName       string
+	paramType       string
+	leaves          map[string][]*ResponseData
+}
+
+// newRouteNode - creates an empty route node
+func newRouteNode() *routeNode {
+
+	return &routeNode{
+		literalChildren: map[string]*routeNode{},
+		leaves:          map[string][]*ResponseData{},
+	}
+}
+
+// routeTrie - indexes every configured response of a mode by its URI pattern
+type routeTrie struct {
+	root *routeNode
+}
+
+// newRouteTrie - builds a routing trie from the responses configured for a mode
+func newRouteTrie(responses []ResponseData) *routeTrie {
+
+	trie := &routeTrie{root: newRouteNode()}
+
+	for i := range responses {
+		trie.add(&responses[i])
+	}
+
+	return trie
+}
+
+// add - indexes a single response by its (possibly patterned) URI
+func (t *routeTrie) add(response *ResponseData) {
+
+	node := t.root
+
+	for _, segment := range splitURI(response.URI) {
+
+		name, paramType, isParam := parsePathParam(segment)
+
+		if !isParam {
+			child, ok := node.literalChildren[segment]
+			if !ok {
+				child = newRouteNode()
+				node.literalChildren[segment] = child
+			}
+			node = child
+			continue
+		}
+
+		if node.paramChild == nil {
+			node.paramChild = newRouteNode()
+			node.paramChild.paramName = name
+			node.paramChild.paramType = paramType
+		}
+		node = node.paramChild
+	}
+
+	node.leaves[response.Method] = append(node.leaves[response.Method], response)
+}
+
+// find - resolves the best matching response for the given method, URI, query
+// and body, returning the response and the path parameters captured on the way.
+// Literal segments are always preferred over path parameters (longest-literal-
+// prefix), falling back to parameters only when no literal branch satisfies the
+// request - this keeps plain, pattern-free URIs matching exactly as before.
+func (t *routeTrie) find(method, uri string, query url.Values, body string) (*ResponseData, map[string]string) {
+
+	params := map[string]string{}
+	response := t.root.find(splitURI(uri), 0, params, method, query, body)
+	if response == nil {
+		return nil, nil
+	}
+
+	return response, params
+}
+
+// find - recursively walks the trie trying literal children before the
+// parameter child, backtracking whenever a branch fails to yield a response
+// that also satisfies the candidate's query/body constraints
+func (n *routeNode) find(segments []string, idx int, params map[string]string, method string, query url.Values, body string) *ResponseData {
+
+	if idx == len(segments) {
+		return bestLeaf(n.leaves[method], query, body)
+	}
+
+	segment := segments[idx]
+
+	if child, ok := n.literalChildren[segment]; ok {
+		if response := child.find(segments, idx+1, params, method, query, body); response != nil {
+			return response
+		}
+	}
+
+	if n.paramChild != nil && matchesParamType(n.paramChild.paramType, segment) {
+		params[n.paramChild.paramName] = segment
+		if response := n.paramChild.find(segments, idx+1, params, method, query, body); response != nil {
+			return response
+		}
+		delete(params, n.paramChild.paramName)
+	}
+
+	return nil
+}
+
+// bestLeaf - among the responses sharing a path pattern and method, returns the
+// one satisfying its QueryMatch/BodyMatch constraints with the highest
+// specificity (most constraints configured), so a constrained response wins
+// over an unconstrained fallback registered for the same path
+func bestLeaf(candidates []*ResponseData, query url.Values, body string) *ResponseData {
+
+	var best *ResponseData
+	bestScore := -1
+
+	for _, candidate := range candidates {
+
+		if !matchesConstraints(candidate, query, body) {
+			continue
+		}
+
+		score := len(candidate.QueryMatch)
+		if candidate.BodyMatch != "" {
+			score++
+		}
+
+		if score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+// matchesParamType - validates a captured segment against the wildcard's declared type
+func matchesParamType(paramType, value string) bool {
+
+	if paramType == "" {
+		return true
+	}
+
+	validator, ok := paramTypeValidators[paramType]
+	if !ok {
+		return true
+	}
+
+	return validator.MatchString(value)
+}
+
+// matchesConstraints - checks the optional QueryMatch and BodyMatch constraints
+// configured on a response candidate against the incoming request
+func matchesConstraints(candidate *ResponseData, query url.Values, body string) bool {
+
+	for key, value := range candidate.QueryMatch {
+		if query.Get(key) != value {
+			return false
+		}
+	}
+
+	if candidate.BodyMatch != "" && !bodyMatches(candidate.BodyMatch, body) {
+		return false
+	}
+
+	return true
+}
+
+// bodyMatches - matches a request body against a BodyMatch pattern, which may
+// be a regular expression or, when it looks like a JSON object, a JSON subset
+func bodyMatches(pattern, body string) bool {
+
+	trimmed := strings.TrimSpace(pattern)
+
+	if strings.HasPrefix(trimmed, "{") {
+
+		var expected, actual map[string]interface{}
+
+		if json.Unmarshal([]byte(pattern), &expected) != nil {
+			return false
+		}
+
+		if json.Unmarshal([]byte(body), &actual) != nil {
+			return false
+		}
+
+		return jsonSubset(expected, actual)
+	}
+
+	matched, err := regexp.MatchString(pattern, body)
+
+	return err == nil && matched
+}
+
+// jsonSubset - checks that every field of subset is present and equal in full,
+// recursing into nested objects
+func jsonSubset(subset, full map[string]interface{}) bool {
+
+	for key, expected := range subset {
+
+		actual, ok := full[key]
+		if !ok {
+			return false
+		}
+
+		expectedObj, isObj := expected.(map[string]interface{})
+		if !isObj {
+			if !reflect.DeepEqual(expected, actual) {
+				return false
+			}
+			continue
+		}
+
+		actualObj, ok := actual.(map[string]interface{})
+		if !ok || !jsonSubset(expectedObj, actualObj) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parsePathParam - parses a "{name}" or "{name:type}" URI segment
+func parsePathParam(segment string) (name string, paramType string, isParam bool) {
+
+	if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+		return "", "", false
+	}
+
+	inner := segment[1 : len(segment)-1]
+
+	if colon := strings.IndexByte(inner, ':'); colon >= 0 {
+		return inner[:colon], inner[colon+1:], true
+	}
+
+	return inner, "", true
+}
+
+// splitURI - splits a URI path into its non-empty segments
+func splitURI(uri string) []string {
+
+	parts := strings.Split(strings.Trim(uri, "/"), "/")
+
+	segments := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			segments = append(segments, part)
+		}
+	}
+
+	return segments
+}
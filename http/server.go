@@ -0,0 +1,323 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/**
+* A mocked HTTP server used to test HTTP clients.
+* @author rnojiri
+**/
+
+// Server - a mocked HTTP server driven by a configuration
+type Server struct {
+	configuration *Configuration
+	httpServer    *http.Server
+	requests      chan *RequestData
+	routers       map[string]*routeTrie
+	replayIndex   map[string]*ResponseData
+	scripts       map[string]map[string]*scriptState
+	handlers      map[string]map[string]HandlerFunc
+	state         *ServerState
+	mode          string
+	mutex         sync.RWMutex
+	tls           *generatedTLS
+}
+
+// NewServer - creates a new mocked HTTP server and starts listening
+func NewServer(configuration *Configuration) *Server {
+
+	routers := map[string]*routeTrie{}
+	for mode, responses := range configuration.Responses {
+		routers[mode] = newRouteTrie(responses)
+	}
+
+	scripts := map[string]map[string]*scriptState{}
+	for mode, modeScripts := range configuration.Scripts {
+		byRoute := map[string]*scriptState{}
+		for i := range modeScripts {
+			byRoute[routeKey(modeScripts[i].Method, modeScripts[i].URI)] = &scriptState{script: &modeScripts[i]}
+		}
+		scripts[mode] = byRoute
+	}
+
+	handlers := map[string]map[string]HandlerFunc{}
+	for mode, modeHandlers := range configuration.Handlers {
+		byRoute := map[string]HandlerFunc{}
+		for _, h := range modeHandlers {
+			byRoute[routeKey(h.Method, h.URI)] = h.Handler
+		}
+		handlers[mode] = byRoute
+	}
+
+	s := &Server{
+		configuration: configuration,
+		requests:      make(chan *RequestData, configuration.ChannelSize),
+		routers:       routers,
+		scripts:       scripts,
+		handlers:      handlers,
+		state:         NewServerState(),
+		mode:          "default",
+	}
+
+	if configuration.Replay != nil {
+		index, err := loadTranscript(configuration.Replay.TranscriptPath)
+		if err != nil {
+			panic(err)
+		}
+		s.replayIndex = index
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handle)
+
+	s.httpServer = &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", configuration.Host, configuration.Port),
+		Handler: mux,
+	}
+
+	if configuration.TLS != nil {
+		tlsConfig, generated, err := setupTLS(configuration.TLS, configuration.Host)
+		if err != nil {
+			panic(err)
+		}
+		s.tls = generated
+		s.httpServer.TLSConfig = tlsConfig
+	}
+
+	listening := make(chan struct{})
+
+	go func() {
+		close(listening)
+		if s.httpServer.TLSConfig != nil {
+			s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			s.httpServer.ListenAndServe()
+		}
+	}()
+
+	<-listening
+	time.Sleep(50 * time.Millisecond)
+
+	return s
+}
+
+// SetMode - changes the current response mode
+func (s *Server) SetMode(mode string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.mode = mode
+}
+
+// currentMode - returns the current response mode
+func (s *Server) currentMode() string {
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.mode
+}
+
+// State - returns the server's shared state, for HandlerFunc implementations
+// to read or mutate across calls
+func (s *Server) State() *ServerState {
+
+	return s.state
+}
+
+// ClientCertPool - returns a pool trusting the self-signed CA generated for
+// this server's TLS setup, for DoTLSRequest to verify its certificate.
+// Returns nil when the server isn't running TLS or was configured with
+// user-supplied certificates.
+func (s *Server) ClientCertPool() *x509.CertPool {
+
+	if s.tls == nil || len(s.tls.caCertPEM) == 0 {
+		return nil
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(s.tls.caCertPEM)
+
+	return pool
+}
+
+// ClientCertificate - returns the client certificate generated to satisfy
+// this server's ClientAuth requirement, for DoTLSRequest to present. Returns
+// nil when the server isn't running mTLS or a ClientCACertPEM was supplied.
+func (s *Server) ClientCertificate() *tls.Certificate {
+
+	if s.tls == nil {
+		return nil
+	}
+
+	return s.tls.clientCert
+}
+
+// routeKey - builds the lookup key used by the scripts and handlers indexes
+func routeKey(method, uri string) string {
+
+	return method + " " + uri
+}
+
+// handle - handles all incoming requests
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+
+	body, _ := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	received := &RequestData{
+		URI:     r.URL.Path,
+		Body:    string(body),
+		Method:  r.Method,
+		Headers: r.Header,
+		Host:    s.configuration.Host,
+		Port:    s.configuration.Port,
+	}
+
+	if contentType := r.Header.Get("Content-Type"); isMultipart(contentType) {
+		if parts, err := parseMultipartBody(contentType, body); err == nil {
+			received.Parts = parts
+		}
+	}
+
+	var response *ResponseData
+
+	mode := s.currentMode()
+	key := routeKey(received.Method, received.URI)
+
+	if router, ok := s.routers[mode]; ok {
+		response, received.PathParams = router.find(received.Method, received.URI, r.URL.Query(), received.Body)
+	}
+
+	if response == nil {
+		if script, ok := s.scripts[mode][key]; ok {
+			if scripted, ok := script.next(); ok {
+				response = &scripted
+			}
+		}
+	}
+
+	if response == nil {
+		if handler, ok := s.handlers[mode][key]; ok {
+			computed := handler(received, s.state)
+			response = &computed
+		}
+	}
+
+	if response == nil && s.replayIndex != nil {
+		response = s.replayIndex[replaySignature(s.configuration.Replay.Scrub, received)]
+	}
+
+	select {
+	case s.requests <- received:
+	default:
+	}
+
+	if response == nil && s.configuration.Recorder != nil {
+		s.recordAndRespond(w, received)
+		return
+	}
+
+	if response == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.applyResponse(w, response)
+}
+
+// recordAndRespond - proxies an unmatched request to the recorder's upstream,
+// appends the round-trip to its transcript file and relays the response back
+func (s *Server) recordAndRespond(w http.ResponseWriter, request *RequestData) {
+
+	recorder := s.configuration.Recorder
+
+	response, duration, err := proxyToUpstream(recorder.UpstreamBaseURL, request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	entry := &TranscriptEntry{
+		Request:   *request,
+		Response:  *response,
+		Timestamp: time.Now(),
+		Duration:  duration,
+	}
+
+	if err := appendTranscript(recorder.TranscriptPath, entry, recorder.Scrub); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for header, values := range response.Headers {
+		for _, value := range values {
+			w.Header().Add(header, value)
+		}
+	}
+
+	w.WriteHeader(response.Status)
+	w.Write([]byte(response.Body))
+}
+
+// Close - stops the mocked HTTP server
+func (s *Server) Close() {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s.httpServer.Shutdown(ctx)
+}
+
+// WaitForServerRequest - polls the server's received requests channel every waitTime,
+// giving up once timeout has elapsed
+func WaitForServerRequest(server *Server, waitTime, timeout time.Duration) *RequestData {
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		select {
+		case request := <-server.requests:
+			return request
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			return nil
+		}
+
+		time.Sleep(waitTime)
+	}
+}
+
+// WaitForMultipartRequest - like WaitForServerRequest, but skips requests that
+// were not parsed as multipart, giving up once timeout has elapsed
+func WaitForMultipartRequest(server *Server, waitTime, timeout time.Duration) *RequestData {
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		select {
+		case request := <-server.requests:
+			if len(request.Parts) > 0 {
+				return request
+			}
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			return nil
+		}
+
+		time.Sleep(waitTime)
+	}
+}
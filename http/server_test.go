@@ -1,8 +1,13 @@
 package http_test
 
 import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
 	"math"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -295,3 +300,672 @@ func TestWaitResponse(t *testing.T) {
 	serverRequest := gotesthttp.WaitForServerRequest(server, time.Duration(randomSeconds+1)*time.Second, 10*time.Second)
 	compareRequests(t, clientRequest, serverRequest)
 }
+
+// TestPathParams - tests routing a pattern URI and capturing its path parameters
+func TestPathParams(t *testing.T) {
+
+	response := createDummyResponse()
+	response.URI = "/users/{id}/orders/{oid}"
+	response.Method = "GET"
+
+	defaultConf.Responses = map[string][]gotesthttp.ResponseData{
+		"default": {response},
+	}
+
+	server := gotesthttp.NewServer(&defaultConf)
+	defer server.Close()
+
+	serverResponse := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, &gotesthttp.RequestData{
+		URI:    "/users/42/orders/7",
+		Method: "GET",
+	})
+
+	assert.Equal(t, http.StatusOK, serverResponse.Status, "expected 200 status")
+
+	serverRequest := gotesthttp.WaitForServerRequest(server, time.Second, 10*time.Second)
+	if !assert.NotNil(t, serverRequest, "expected a received request") {
+		return
+	}
+
+	assert.Equal(t, map[string]string{"id": "42", "oid": "7"}, serverRequest.PathParams, "expected captured path parameters")
+}
+
+// TestQueryAndBodyMatch - tests constraining a response by query parameters and body content
+func TestQueryAndBodyMatch(t *testing.T) {
+
+	matching := createDummyResponse()
+	matching.URI = "/search"
+	matching.Method = "POST"
+	matching.QueryMatch = map[string]string{"scope": "orders"}
+	matching.BodyMatch = `{"term": "test"}`
+	matching.Status = http.StatusOK
+
+	fallback := createDummyResponse()
+	fallback.URI = "/search"
+	fallback.Method = "POST"
+	fallback.Status = http.StatusBadRequest
+
+	defaultConf.Responses = map[string][]gotesthttp.ResponseData{
+		"default": {fallback, matching},
+	}
+
+	server := gotesthttp.NewServer(&defaultConf)
+	defer server.Close()
+
+	serverResponse := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, &gotesthttp.RequestData{
+		URI:    "/search?scope=orders",
+		Method: "POST",
+		Body:   `{"term": "test", "page": 1}`,
+	})
+
+	assert.Equal(t, http.StatusOK, serverResponse.Status, "expected the constrained response to match")
+
+	serverResponse = gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, &gotesthttp.RequestData{
+		URI:    "/search?scope=users",
+		Method: "POST",
+		Body:   `{"term": "test", "page": 1}`,
+	})
+
+	assert.Equal(t, http.StatusBadRequest, serverResponse.Status, "expected the fallback response when query does not match")
+}
+
+// TestLiteralBeatsPathParam - tests that a literal segment wins over an
+// overlapping {param} segment registered at the same depth
+func TestLiteralBeatsPathParam(t *testing.T) {
+
+	literal := createDummyResponse()
+	literal.URI = "/users/active"
+	literal.Method = "GET"
+	literal.Status = http.StatusOK
+
+	param := createDummyResponse()
+	param.URI = "/users/{id}"
+	param.Method = "GET"
+	param.Status = http.StatusTeapot
+
+	defaultConf.Responses = map[string][]gotesthttp.ResponseData{
+		"default": {param, literal},
+	}
+
+	server := gotesthttp.NewServer(&defaultConf)
+	defer server.Close()
+
+	literalResponse := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, &gotesthttp.RequestData{
+		URI:    "/users/active",
+		Method: "GET",
+	})
+
+	assert.Equal(t, http.StatusOK, literalResponse.Status, "expected the literal segment to win over the overlapping path parameter")
+
+	paramResponse := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, &gotesthttp.RequestData{
+		URI:    "/users/42",
+		Method: "GET",
+	})
+
+	assert.Equal(t, http.StatusTeapot, paramResponse.Status, "expected the path parameter to still match non-literal segments")
+}
+
+// TestQueryMatchSpecificity - tests that among several constrained responses
+// satisfying the same request, the one with the most constraints configured wins
+func TestQueryMatchSpecificity(t *testing.T) {
+
+	lessSpecific := createDummyResponse()
+	lessSpecific.URI = "/search"
+	lessSpecific.Method = "POST"
+	lessSpecific.QueryMatch = map[string]string{"scope": "orders"}
+	lessSpecific.Status = http.StatusOK
+
+	moreSpecific := createDummyResponse()
+	moreSpecific.URI = "/search"
+	moreSpecific.Method = "POST"
+	moreSpecific.QueryMatch = map[string]string{"scope": "orders"}
+	moreSpecific.BodyMatch = `{"term": "test"}`
+	moreSpecific.Status = http.StatusCreated
+
+	defaultConf.Responses = map[string][]gotesthttp.ResponseData{
+		"default": {lessSpecific, moreSpecific},
+	}
+
+	server := gotesthttp.NewServer(&defaultConf)
+	defer server.Close()
+
+	serverResponse := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, &gotesthttp.RequestData{
+		URI:    "/search?scope=orders",
+		Method: "POST",
+		Body:   `{"term": "test", "page": 1}`,
+	})
+
+	assert.Equal(t, http.StatusCreated, serverResponse.Status, "expected the more specific (QueryMatch+BodyMatch) response to win over the QueryMatch-only one")
+}
+
+// TestRecordAndReplay - tests proxying an unmatched request upstream, recording it
+// to a transcript file, then serving it back deterministically from that transcript
+func TestRecordAndReplay(t *testing.T) {
+
+	transcriptPath := filepath.Join(t.TempDir(), "transcript.ndjson")
+
+	upstreamConf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18081,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/ping", Method: "GET", Body: "pong"},
+					Status:      http.StatusOK,
+				},
+			},
+		},
+	}
+
+	upstream := gotesthttp.NewServer(&upstreamConf)
+	defer upstream.Close()
+
+	recorderConf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18082,
+		ChannelSize: 5,
+		Recorder: &gotesthttp.RecorderMode{
+			UpstreamBaseURL: fmt.Sprintf("http://%s:%d", upstreamConf.Host, upstreamConf.Port),
+			TranscriptPath:  transcriptPath,
+		},
+	}
+
+	recorder := gotesthttp.NewServer(&recorderConf)
+
+	recordedResponse := gotesthttp.DoRequest(recorderConf.Host, recorderConf.Port, &gotesthttp.RequestData{
+		URI:    "/ping",
+		Method: "GET",
+	})
+
+	recorder.Close()
+
+	assert.Equal(t, http.StatusOK, recordedResponse.Status, "expected the proxied upstream response")
+	assert.Equal(t, "pong", recordedResponse.Body, "expected the proxied upstream body")
+
+	_, err := os.Stat(transcriptPath)
+	assert.NoError(t, err, "expected a transcript file to be written")
+
+	replayConf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18083,
+		ChannelSize: 5,
+		Replay: &gotesthttp.ReplayMode{
+			TranscriptPath: transcriptPath,
+		},
+	}
+
+	replay := gotesthttp.NewServer(&replayConf)
+	defer replay.Close()
+
+	replayedResponse := gotesthttp.DoRequest(replayConf.Host, replayConf.Port, &gotesthttp.RequestData{
+		URI:    "/ping",
+		Method: "GET",
+	})
+
+	assert.Equal(t, http.StatusOK, replayedResponse.Status, "expected the replayed response")
+	assert.Equal(t, "pong", replayedResponse.Body, "expected the replayed body")
+}
+
+// TestRecordScrub - tests that Recorder.Scrub redacts a secret from both the
+// request body and headers before the transcript file is written
+func TestRecordScrub(t *testing.T) {
+
+	transcriptPath := filepath.Join(t.TempDir(), "transcript.ndjson")
+
+	const secret = "s3cr3t-token"
+
+	upstreamConf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18086,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/ping", Method: "GET", Body: "pong"},
+					Status:      http.StatusOK,
+				},
+			},
+		},
+	}
+
+	upstream := gotesthttp.NewServer(&upstreamConf)
+	defer upstream.Close()
+
+	scrub := func(request *gotesthttp.RequestData) []byte {
+		request.Headers.Set("Authorization", "[redacted]")
+		return []byte(strings.Replace(request.Body, secret, "[redacted]", 1))
+	}
+
+	recorderConf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18087,
+		ChannelSize: 5,
+		Recorder: &gotesthttp.RecorderMode{
+			UpstreamBaseURL: fmt.Sprintf("http://%s:%d", upstreamConf.Host, upstreamConf.Port),
+			TranscriptPath:  transcriptPath,
+			Scrub:           scrub,
+		},
+	}
+
+	recorder := gotesthttp.NewServer(&recorderConf)
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer "+secret)
+
+	gotesthttp.DoRequest(recorderConf.Host, recorderConf.Port, &gotesthttp.RequestData{
+		URI:     "/ping",
+		Method:  "GET",
+		Body:    "token=" + secret,
+		Headers: headers,
+	})
+
+	recorder.Close()
+
+	transcript, err := ioutil.ReadFile(transcriptPath)
+	if !assert.NoError(t, err, "expected a transcript file to be written") {
+		return
+	}
+
+	assert.NotContains(t, string(transcript), secret, "expected the secret to be redacted from the transcript file")
+
+	replayConf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18090,
+		ChannelSize: 5,
+		Replay: &gotesthttp.ReplayMode{
+			TranscriptPath: transcriptPath,
+			Scrub:          scrub,
+		},
+	}
+
+	replay := gotesthttp.NewServer(&replayConf)
+	defer replay.Close()
+
+	replayedResponse := gotesthttp.DoRequest(replayConf.Host, replayConf.Port, &gotesthttp.RequestData{
+		URI:    "/ping",
+		Method: "GET",
+		Body:   "token=" + secret,
+	})
+
+	assert.Equal(t, http.StatusOK, replayedResponse.Status, "expected the live unscrubbed request to still match the scrubbed, recorded signature")
+	assert.Equal(t, "pong", replayedResponse.Body, "expected the replayed body")
+}
+
+// TestResponseScript - tests a round-robin scripted sequence of responses
+func TestResponseScript(t *testing.T) {
+
+	defaultConf.Responses = nil
+	defaultConf.Scripts = map[string][]gotesthttp.ResponseScript{
+		"default": {
+			{
+				URI:    "/retry",
+				Method: "GET",
+				Mode:   gotesthttp.ScriptRoundRobin,
+				Sequence: []gotesthttp.ResponseData{
+					{Status: http.StatusServiceUnavailable},
+					{Status: http.StatusServiceUnavailable},
+					{Status: http.StatusOK, RequestData: gotesthttp.RequestData{Body: "done"}},
+				},
+			},
+		},
+	}
+	defer func() { defaultConf.Scripts = nil }()
+
+	server := gotesthttp.NewServer(&defaultConf)
+	defer server.Close()
+
+	request := &gotesthttp.RequestData{URI: "/retry", Method: "GET"}
+
+	statuses := []int{
+		gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, request).Status,
+		gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, request).Status,
+		gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, request).Status,
+	}
+
+	assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}, statuses, "expected the scripted sequence")
+
+	// round robin: the fourth call wraps back to the first response
+	wrapped := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, request)
+	assert.Equal(t, http.StatusServiceUnavailable, wrapped.Status, "expected the sequence to cycle back to the start")
+}
+
+// TestHandlerFunc - tests a dynamic handler that counts calls via the server's shared state
+func TestHandlerFunc(t *testing.T) {
+
+	defaultConf.Responses = nil
+	defaultConf.Scripts = nil
+	defaultConf.Handlers = map[string][]gotesthttp.RouteHandler{
+		"default": {
+			{
+				URI:    "/count",
+				Method: "GET",
+				Handler: func(req *gotesthttp.RequestData, state *gotesthttp.ServerState) gotesthttp.ResponseData {
+					calls := state.Incr("calls")
+					return gotesthttp.ResponseData{
+						Status:      http.StatusOK,
+						RequestData: gotesthttp.RequestData{Body: fmt.Sprintf("%d", calls)},
+					}
+				},
+			},
+		},
+	}
+	defer func() { defaultConf.Handlers = nil }()
+
+	server := gotesthttp.NewServer(&defaultConf)
+	defer server.Close()
+
+	request := &gotesthttp.RequestData{URI: "/count", Method: "GET"}
+
+	first := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, request)
+	second := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, request)
+
+	assert.Equal(t, "1", first.Body, "expected the first call to report one")
+	assert.Equal(t, "2", second.Body, "expected the second call to report two")
+
+	calls, ok := server.State().Get("calls")
+	assert.True(t, ok, "expected the counter to be present in the server state")
+	assert.Equal(t, 2, calls, "expected the shared state to reflect the handler's increments")
+}
+
+// TestMultipartRequest - tests sending and parsing a multipart request
+func TestMultipartRequest(t *testing.T) {
+
+	defaultConf.Responses = map[string][]gotesthttp.ResponseData{
+		"default": {
+			{
+				RequestData: gotesthttp.RequestData{URI: "/upload", Method: "POST"},
+				Status:      http.StatusOK,
+			},
+		},
+	}
+
+	server := gotesthttp.NewServer(&defaultConf)
+	defer server.Close()
+
+	parts := []gotesthttp.MultipartPart{
+		{MediaType: "text/plain", Body: "hello"},
+		{MediaType: "application/octet-stream", Body: "binary-content", FileName: "data.bin"},
+	}
+
+	serverResponse := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, &gotesthttp.RequestData{
+		URI:    "/upload",
+		Method: "POST",
+		Parts:  parts,
+	})
+
+	assert.Equal(t, http.StatusOK, serverResponse.Status, "expected 200 status")
+
+	serverRequest := gotesthttp.WaitForMultipartRequest(server, time.Second, 10*time.Second)
+	if !assert.NotNil(t, serverRequest, "expected a received multipart request") {
+		return
+	}
+
+	assert.True(t, gotesthttp.EqualMultipartParts(parts, serverRequest.Parts), "expected the parsed parts to match what was sent")
+}
+
+// TestMultipartDistinctFieldNames - tests that multiple parts of the same
+// kind (plain or file), left to default naming, don't collide on one field name
+func TestMultipartDistinctFieldNames(t *testing.T) {
+
+	defaultConf.Responses = map[string][]gotesthttp.ResponseData{
+		"default": {
+			{
+				RequestData: gotesthttp.RequestData{URI: "/upload", Method: "POST"},
+				Status:      http.StatusOK,
+			},
+		},
+	}
+
+	server := gotesthttp.NewServer(&defaultConf)
+	defer server.Close()
+
+	parts := []gotesthttp.MultipartPart{
+		{MediaType: "text/plain", Body: "first"},
+		{MediaType: "text/plain", Body: "second"},
+		{MediaType: "application/octet-stream", Body: "a", FileName: "a.bin"},
+		{MediaType: "application/octet-stream", Body: "b", FileName: "b.bin"},
+	}
+
+	serverResponse := gotesthttp.DoRequest(defaultConf.Host, defaultConf.Port, &gotesthttp.RequestData{
+		URI:    "/upload",
+		Method: "POST",
+		Parts:  parts,
+	})
+
+	assert.Equal(t, http.StatusOK, serverResponse.Status, "expected 200 status")
+
+	serverRequest := gotesthttp.WaitForMultipartRequest(server, time.Second, 10*time.Second)
+	if !assert.NotNil(t, serverRequest, "expected a received multipart request") {
+		return
+	}
+
+	if !assert.Len(t, serverRequest.Parts, 4, "expected all four parts to be parsed") {
+		return
+	}
+
+	names := make(map[string]bool, len(serverRequest.Parts))
+	for _, part := range serverRequest.Parts {
+		assert.False(t, names[part.Name], "expected field name %q to be used by only one part", part.Name)
+		names[part.Name] = true
+	}
+}
+
+// TestTLSServer - tests serving requests over HTTPS with a generated self-signed certificate
+func TestTLSServer(t *testing.T) {
+
+	conf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18081,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/secure", Method: "GET"},
+					Status:      http.StatusOK,
+				},
+			},
+		},
+		TLS: &gotesthttp.TLSConfig{},
+	}
+
+	server := gotesthttp.NewServer(&conf)
+	defer server.Close()
+
+	response := gotesthttp.DoTLSRequest(conf.Host, conf.Port, &gotesthttp.RequestData{
+		URI:    "/secure",
+		Method: "GET",
+	}, server.ClientCertPool(), nil)
+
+	assert.Equal(t, http.StatusOK, response.Status, "expected 200 status")
+}
+
+// TestTLSServerMutualAuth - tests a server requiring and verifying a client certificate
+func TestTLSServerMutualAuth(t *testing.T) {
+
+	conf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18082,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/secure", Method: "GET"},
+					Status:      http.StatusOK,
+				},
+			},
+		},
+		TLS: &gotesthttp.TLSConfig{ClientAuth: tls.RequireAndVerifyClientCert},
+	}
+
+	server := gotesthttp.NewServer(&conf)
+	defer server.Close()
+
+	response := gotesthttp.DoTLSRequest(conf.Host, conf.Port, &gotesthttp.RequestData{
+		URI:    "/secure",
+		Method: "GET",
+	}, server.ClientCertPool(), server.ClientCertificate())
+
+	assert.Equal(t, http.StatusOK, response.Status, "expected 200 status")
+}
+
+// TestFaultsPartialBody - tests that Faults.PartialBody truncates the response body
+func TestFaultsPartialBody(t *testing.T) {
+
+	conf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18083,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/flaky", Method: "GET", Body: "0123456789"},
+					Status:      http.StatusOK,
+					Faults:      &gotesthttp.Faults{PartialBody: 4},
+				},
+			},
+		},
+	}
+
+	server := gotesthttp.NewServer(&conf)
+	defer server.Close()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s:%d/flaky", conf.Host, conf.Port))
+	if !assert.NoError(t, err, "expected the request to succeed") {
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, "0123", string(body), "expected the body to be truncated to PartialBody bytes")
+}
+
+// TestFaultsDropConnection - tests that Faults.DropConnection surfaces as a client-side error
+func TestFaultsDropConnection(t *testing.T) {
+
+	conf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18084,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/flaky", Method: "GET"},
+					Status:      http.StatusOK,
+					Faults:      &gotesthttp.Faults{DropConnection: true},
+				},
+			},
+		},
+	}
+
+	server := gotesthttp.NewServer(&conf)
+	defer server.Close()
+
+	_, err := http.Get(fmt.Sprintf("http://%s:%d/flaky", conf.Host, conf.Port))
+
+	assert.Error(t, err, "expected the dropped connection to surface as a client error")
+}
+
+// TestFaultsRandomStatus - tests that Faults.RandomStatus overrides the configured status
+func TestFaultsRandomStatus(t *testing.T) {
+
+	conf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18085,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/flaky", Method: "GET"},
+					Status:      http.StatusOK,
+					Faults: &gotesthttp.Faults{
+						RandomStatus: []gotesthttp.WeightedStatus{
+							{Status: http.StatusTeapot, Weight: 1},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	server := gotesthttp.NewServer(&conf)
+	defer server.Close()
+
+	response := gotesthttp.DoRequest(conf.Host, conf.Port, &gotesthttp.RequestData{
+		URI:    "/flaky",
+		Method: "GET",
+	})
+
+	assert.Equal(t, http.StatusTeapot, response.Status, "expected the single weighted candidate to always be picked")
+}
+
+// TestFaultsSlowBody - tests that Faults.SlowBody trickles the body out over roughly len(body)*delay
+func TestFaultsSlowBody(t *testing.T) {
+
+	const delay = 20 * time.Millisecond
+
+	conf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18088,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/flaky", Method: "GET", Body: "abcde"},
+					Status:      http.StatusOK,
+					Faults:      &gotesthttp.Faults{SlowBody: delay},
+				},
+			},
+		},
+	}
+
+	server := gotesthttp.NewServer(&conf)
+	defer server.Close()
+
+	start := time.Now()
+	response := gotesthttp.DoRequest(conf.Host, conf.Port, &gotesthttp.RequestData{
+		URI:    "/flaky",
+		Method: "GET",
+	})
+	elapsed := time.Since(start)
+
+	assert.Equal(t, "abcde", response.Body, "expected the full body to eventually arrive")
+	assert.GreaterOrEqual(t, elapsed, 5*delay, "expected the body to be trickled out one byte every delay")
+}
+
+// TestFaultsJitterWait - tests that Faults.JitterWait adds noise in [0, JitterWait) on top of Wait
+func TestFaultsJitterWait(t *testing.T) {
+
+	const wait = 50 * time.Millisecond
+	const jitter = 100 * time.Millisecond
+
+	conf := gotesthttp.Configuration{
+		Host:        "localhost",
+		Port:        18089,
+		ChannelSize: 5,
+		Responses: map[string][]gotesthttp.ResponseData{
+			"default": {
+				{
+					RequestData: gotesthttp.RequestData{URI: "/flaky", Method: "GET"},
+					Status:      http.StatusOK,
+					Wait:        wait,
+					Faults:      &gotesthttp.Faults{JitterWait: jitter},
+				},
+			},
+		},
+	}
+
+	server := gotesthttp.NewServer(&conf)
+	defer server.Close()
+
+	start := time.Now()
+	gotesthttp.DoRequest(conf.Host, conf.Port, &gotesthttp.RequestData{
+		URI:    "/flaky",
+		Method: "GET",
+	})
+	elapsed := time.Since(start)
+
+	assert.GreaterOrEqual(t, elapsed, wait, "expected the response to wait at least Wait")
+	assert.Less(t, elapsed, wait+jitter+50*time.Millisecond, "expected the jitter to stay within Wait+JitterWait plus scheduling slack")
+}
@@ -0,0 +1,148 @@
+package http
+
+import "sync"
+
+/**
+* Scriptable response sequencing and dynamically computed responses, for
+* tests that need retries, pagination or other stateful call sequences.
+* @author rnojiri
+**/
+
+// ScriptCycleMode - how a ResponseScript cycles through its sequence once
+// every configured response has been served
+type ScriptCycleMode int
+
+const (
+	// ScriptOnce - serves the sequence once; further calls are treated as unmatched
+	ScriptOnce ScriptCycleMode = iota
+
+	// ScriptRepeat - keeps serving the last response of the sequence indefinitely
+	ScriptRepeat
+
+	// ScriptRoundRobin - cycles back to the first response after the sequence ends
+	ScriptRoundRobin
+)
+
+// ResponseScript - an ordered sequence of responses served for a given
+// (URI, Method) pair, one per call, cycling according to Mode
+type ResponseScript struct {
+	URI      string
+	Method   string
+	Sequence []ResponseData
+	Mode     ScriptCycleMode
+}
+
+// scriptState - tracks how far a ResponseScript has been consumed
+type scriptState struct {
+	script *ResponseScript
+	mutex  sync.Mutex
+	index  int
+}
+
+// next - returns the next response of the script, and whether the script
+// still has a response to offer for this call
+func (st *scriptState) next() (ResponseData, bool) {
+
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	n := len(st.script.Sequence)
+	if n == 0 {
+		return ResponseData{}, false
+	}
+
+	switch st.script.Mode {
+
+	case ScriptRoundRobin:
+		response := st.script.Sequence[st.index%n]
+		st.index++
+		return response, true
+
+	case ScriptRepeat:
+		index := st.index
+		if index >= n {
+			index = n - 1
+		} else {
+			st.index++
+		}
+		return st.script.Sequence[index], true
+
+	default: // ScriptOnce
+		if st.index >= n {
+			return ResponseData{}, false
+		}
+		response := st.script.Sequence[st.index]
+		st.index++
+		return response, true
+	}
+}
+
+// HandlerFunc - computes a response dynamically for a given request, reading
+// and/or mutating the server's shared state
+type HandlerFunc func(req *RequestData, state *ServerState) ResponseData
+
+// RouteHandler - a HandlerFunc registered for a given (URI, Method) pair
+type RouteHandler struct {
+	URI     string
+	Method  string
+	Handler HandlerFunc
+}
+
+// ServerState - a concurrent-safe key/value bag shared across every request
+// handled by a Server, letting HandlerFunc implementations keep state
+// between calls (e.g. counters, pagination cursors)
+type ServerState struct {
+	mutex sync.RWMutex
+	data  map[string]interface{}
+}
+
+// NewServerState - creates an empty ServerState
+func NewServerState() *ServerState {
+
+	return &ServerState{
+		data: map[string]interface{}{},
+	}
+}
+
+// Get - returns the value stored under key, and whether it was present
+func (s *ServerState) Get(key string) (interface{}, bool) {
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, ok := s.data[key]
+
+	return value, ok
+}
+
+// Set - stores a value under key
+func (s *ServerState) Set(key string, value interface{}) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data[key] = value
+}
+
+// Delete - removes key from the state
+func (s *ServerState) Delete(key string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data, key)
+}
+
+// Incr - increments the int counter stored under key by one and returns its
+// new value, treating a missing key as zero
+func (s *ServerState) Incr(key string) int {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counter, _ := s.data[key].(int)
+	counter++
+	s.data[key] = counter
+
+	return counter
+}
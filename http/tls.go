@@ -0,0 +1,203 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+/**
+* TLS/HTTPS server support, including on-the-fly self-signed certificate
+* generation, so tests can exercise clients that refuse plaintext.
+* @author rnojiri
+**/
+
+// TLSConfig - configures the test server to listen over HTTPS
+type TLSConfig struct {
+	// CertPEM and KeyPEM, when both set, are used as the server's leaf
+	// certificate instead of generating a self-signed one
+	CertPEM []byte
+	KeyPEM  []byte
+
+	// ClientAuth controls whether the server requests/requires a client
+	// certificate, for testing mTLS clients
+	ClientAuth tls.ClientAuthType
+
+	// ClientCACertPEM, when set, is the CA used to verify client
+	// certificates. When ClientAuth requires verification and this is left
+	// empty, the server's own generated CA is reused to also sign a client
+	// certificate, exposed via Server.ClientCertificate.
+	ClientCACertPEM []byte
+}
+
+// generatedTLS - the material produced when auto-generating a self-signed
+// CA and a leaf certificate for the server to present
+type generatedTLS struct {
+	leaf          tls.Certificate
+	caCertPEM     []byte
+	clientCert    *tls.Certificate
+	clientCertPEM []byte
+}
+
+// setupTLS - builds the *tls.Config the server should listen with, and the
+// material that DoTLSRequest needs to trust and, for mTLS, authenticate it
+func setupTLS(conf *TLSConfig, host string) (*tls.Config, *generatedTLS, error) {
+
+	if len(conf.CertPEM) > 0 && len(conf.KeyPEM) > 0 {
+
+		leaf, err := tls.X509KeyPair(conf.CertPEM, conf.KeyPEM)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tlsConfig := &tls.Config{
+			Certificates: []tls.Certificate{leaf},
+			ClientAuth:   conf.ClientAuth,
+		}
+
+		if len(conf.ClientCACertPEM) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(conf.ClientCACertPEM) {
+				return nil, nil, fmt.Errorf("gotesthttp: failed to parse ClientCACertPEM")
+			}
+			tlsConfig.ClientCAs = pool
+		}
+
+		return tlsConfig, &generatedTLS{leaf: leaf}, nil
+	}
+
+	caCert, caKey, caCertPEM, err := generateSelfSignedCA()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leaf, err := generateLeafCert(host, caCert, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generated := &generatedTLS{leaf: leaf, caCertPEM: caCertPEM}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{leaf},
+		ClientAuth:   conf.ClientAuth,
+	}
+
+	if conf.ClientAuth >= tls.VerifyClientCertIfGiven {
+
+		clientCAPool := x509.NewCertPool()
+
+		if len(conf.ClientCACertPEM) > 0 {
+			if !clientCAPool.AppendCertsFromPEM(conf.ClientCACertPEM) {
+				return nil, nil, fmt.Errorf("gotesthttp: failed to parse ClientCACertPEM")
+			}
+		} else {
+			clientCert, clientCertPEM, err := generateClientCert(caCert, caKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			clientCAPool.AppendCertsFromPEM(caCertPEM)
+			generated.clientCert = clientCert
+			generated.clientCertPEM = clientCertPEM
+		}
+
+		tlsConfig.ClientCAs = clientCAPool
+	}
+
+	return tlsConfig, generated, nil
+}
+
+// generateSelfSignedCA - creates an in-memory self-signed CA certificate
+func generateSelfSignedCA() (*x509.Certificate, *rsa.PrivateKey, []byte, error) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "gotesthttp CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	return cert, key, certPEM, nil
+}
+
+// generateLeafCert - creates a certificate for host, signed by the given CA
+func generateLeafCert(host string, caCert *x509.Certificate, caKey *rsa.PrivateKey) (tls.Certificate, error) {
+
+	return generateSignedCert(host, x509.ExtKeyUsageServerAuth, caCert, caKey)
+}
+
+// generateClientCert - creates a client certificate signed by the given CA,
+// for tests to present back to a server requiring mTLS
+func generateClientCert(caCert *x509.Certificate, caKey *rsa.PrivateKey) (*tls.Certificate, []byte, error) {
+
+	cert, err := generateSignedCert("gotesthttp-client", x509.ExtKeyUsageClientAuth, caCert, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+
+	return &cert, certPEM, nil
+}
+
+// generateSignedCert - creates a leaf certificate for commonName, signed by
+// the given CA, with the given extended key usage
+func generateSignedCert(commonName string, extKeyUsage x509.ExtKeyUsage, caCert *x509.Certificate, caKey *rsa.PrivateKey) (tls.Certificate, error) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{extKeyUsage},
+	}
+
+	if ip := net.ParseIP(commonName); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{commonName}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}